@@ -0,0 +1,137 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import "fmt"
+
+// CRIRuntimeService is the subset of a CRI runtime endpoint's
+// RuntimeService that CRIPodInfoGetter needs. A real implementation is a
+// thin wrapper around the generated CRI gRPC client.
+type CRIRuntimeService interface {
+	ListPodSandbox(filter *PodSandboxFilter) ([]*PodSandboxSummary, error)
+	PodSandboxStatus(podSandboxID string) (*PodSandboxStatus, error)
+	ListContainers(podSandboxID string) ([]*ContainerSummary, error)
+	ContainerStatus(containerID string) (*ContainerStatus, error)
+}
+
+// podUIDLabel is the label kubelet stamps onto every sandbox it asks the
+// runtime to create, carrying the owning pod's UID. It's how a CRI
+// implementation's own PodSandboxId gets mapped back to a Kubernetes pod.
+const podUIDLabel = "io.kubernetes.pod.uid"
+
+// PodSandboxFilter narrows a ListPodSandbox call, mirroring the CRI
+// PodSandboxFilter message.
+type PodSandboxFilter struct {
+	LabelSelector map[string]string
+}
+
+// PodSandboxSummary identifies a sandbox returned by ListPodSandbox.
+type PodSandboxSummary struct {
+	ID string
+}
+
+// PodSandboxStatus mirrors the fields of the CRI PodSandboxStatus response
+// that CRIPodInfoGetter consumes.
+type PodSandboxStatus struct {
+	ID      string
+	Network *PodSandboxNetworkStatus
+}
+
+// PodSandboxNetworkStatus carries the sandbox-assigned pod IP, as reported
+// by the runtime (e.g. from the CNI result the runtime plumbed in when it
+// created the sandbox's network namespace).
+type PodSandboxNetworkStatus struct {
+	Ip string
+}
+
+// ContainerSummary identifies a container running inside a pod sandbox.
+type ContainerSummary struct {
+	ID   string
+	Name string
+}
+
+// CRIPodInfoGetter is a client.PodInfoGetter backed by a CRI-compatible
+// container runtime (e.g. containerd, CRI-O) reached over a runtime
+// endpoint, rather than the Docker-specific "pod infra container" used by
+// the historical Docker PodInfoGetter.
+type CRIPodInfoGetter struct {
+	Runtime CRIRuntimeService
+	// NodeIP is the IP the runtime's node should be reported under. CRI
+	// doesn't surface this itself, so it's supplied by whoever wires up
+	// the getter (e.g. from the kubelet's configured node IP).
+	NodeIP string
+}
+
+// NewCRIPodInfoGetter returns a PodInfoGetter that talks to runtime.
+func NewCRIPodInfoGetter(runtime CRIRuntimeService, nodeIP string) *CRIPodInfoGetter {
+	return &CRIPodInfoGetter{Runtime: runtime, NodeIP: nodeIP}
+}
+
+// GetPodInfo implements PodInfoGetter. host is unused: the runtime
+// endpoint is already scoped to a single node. podID is a Kubernetes pod
+// ID, not a CRI PodSandboxId, so the sandbox is first resolved via
+// ListPodSandbox filtered on the pod UID label kubelet attaches at
+// creation time.
+func (c *CRIPodInfoGetter) GetPodInfo(host, podID string) (PodInfo, error) {
+	sandboxID, err := c.resolveSandboxID(podID)
+	if err != nil {
+		return PodInfo{}, err
+	}
+
+	sandbox, err := c.Runtime.PodSandboxStatus(sandboxID)
+	if err != nil {
+		return PodInfo{}, fmt.Errorf("CRI: getting sandbox status for pod %q: %v", podID, err)
+	}
+	if sandbox == nil {
+		return PodInfo{}, ErrPodInfoNotAvailable
+	}
+
+	info := PodInfo{HostIP: c.NodeIP, Containers: make(map[string]ContainerStatus)}
+	if sandbox.Network != nil {
+		info.PodIP = sandbox.Network.Ip
+	}
+
+	containers, err := c.Runtime.ListContainers(sandbox.ID)
+	if err != nil {
+		return PodInfo{}, fmt.Errorf("CRI: listing containers for sandbox %q: %v", sandbox.ID, err)
+	}
+	for _, container := range containers {
+		status, err := c.Runtime.ContainerStatus(container.ID)
+		if err != nil {
+			return PodInfo{}, fmt.Errorf("CRI: getting container status for %q: %v", container.ID, err)
+		}
+		info.Containers[container.Name] = *status
+	}
+	return info, nil
+}
+
+// resolveSandboxID maps a Kubernetes pod ID to the runtime's own
+// PodSandboxId by listing sandboxes labeled with that pod's UID. It
+// returns ErrPodInfoNotAvailable if the runtime has no matching sandbox
+// (e.g. it hasn't been created yet).
+func (c *CRIPodInfoGetter) resolveSandboxID(podID string) (string, error) {
+	sandboxes, err := c.Runtime.ListPodSandbox(&PodSandboxFilter{
+		LabelSelector: map[string]string{podUIDLabel: podID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("CRI: listing sandboxes for pod %q: %v", podID, err)
+	}
+	if len(sandboxes) == 0 {
+		return "", ErrPodInfoNotAvailable
+	}
+	return sandboxes[0].ID, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import "fmt"
+
+// dockerNetContainerName is the conventional name kubelet gives the "pod
+// infra container" that owns a pod's network namespace; every other
+// container in the pod joins its namespaces.
+const dockerNetContainerName = "net"
+
+// DockerClient is the subset of the Docker API client that
+// DockerPodInfoGetter needs.
+type DockerClient interface {
+	ListContainersByPod(podID string) ([]DockerContainerSummary, error)
+	InspectContainer(containerID string) (*DockerContainer, error)
+}
+
+// DockerContainerSummary identifies one of a pod's containers as returned
+// by a container list call.
+type DockerContainerSummary struct {
+	ID   string
+	Name string
+}
+
+// DockerContainer is the subset of `docker inspect` output
+// DockerPodInfoGetter consumes.
+type DockerContainer struct {
+	State           DockerContainerState
+	NetworkSettings *DockerNetworkSettings
+}
+
+// DockerContainerState mirrors Docker's container State struct.
+type DockerContainerState struct {
+	Running  bool
+	ExitCode int
+}
+
+// DockerNetworkSettings mirrors Docker's container NetworkSettings struct.
+type DockerNetworkSettings struct {
+	IPAddress string
+}
+
+// DockerPodInfoGetter is a PodInfoGetter backed by Docker's "pod infra
+// container" model: a pod's network namespace lives in a container
+// conventionally named "net", and PodIP comes from that container's
+// NetworkSettings rather than from a runtime-level sandbox concept.
+type DockerPodInfoGetter struct {
+	Docker DockerClient
+}
+
+// NewDockerPodInfoGetter returns a PodInfoGetter that talks to docker.
+func NewDockerPodInfoGetter(docker DockerClient) *DockerPodInfoGetter {
+	return &DockerPodInfoGetter{Docker: docker}
+}
+
+// GetPodInfo implements PodInfoGetter. host is unused: docker is assumed
+// to already be scoped to the node the pod is running on.
+func (d *DockerPodInfoGetter) GetPodInfo(host, podID string) (PodInfo, error) {
+	summaries, err := d.Docker.ListContainersByPod(podID)
+	if err != nil {
+		return PodInfo{}, fmt.Errorf("Docker: listing containers for pod %q: %v", podID, err)
+	}
+	if len(summaries) == 0 {
+		return PodInfo{}, ErrPodInfoNotAvailable
+	}
+
+	info := PodInfo{Containers: make(map[string]ContainerStatus)}
+	for _, summary := range summaries {
+		container, err := d.Docker.InspectContainer(summary.ID)
+		if err != nil {
+			return PodInfo{}, fmt.Errorf("Docker: inspecting container %q: %v", summary.ID, err)
+		}
+		if summary.Name == dockerNetContainerName {
+			if container.NetworkSettings != nil {
+				info.PodIP = container.NetworkSettings.IPAddress
+			}
+			continue
+		}
+		info.Containers[summary.Name] = ContainerStatus{
+			Running:  container.State.Running,
+			ExitCode: container.State.ExitCode,
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import "errors"
+
+// ErrPodInfoNotAvailable is returned by a PodInfoGetter when the requested
+// pod has no information available yet (e.g. its sandbox hasn't started).
+var ErrPodInfoNotAvailable = errors.New("no pod info available")
+
+// ContainerStatus is the runtime-agnostic status of a single container
+// within a pod, as reported by a PodInfoGetter.
+type ContainerStatus struct {
+	Running  bool
+	ExitCode int
+}
+
+// PodInfo is the runtime-agnostic snapshot of a pod's live state returned
+// by a PodInfoGetter: the networking derived from the pod's sandbox, and
+// the status of each of its containers keyed by container name.
+type PodInfo struct {
+	PodIP      string
+	HostIP     string
+	Containers map[string]ContainerStatus
+}
+
+// PodInfoGetter is implemented by anything that can report the live state
+// of a pod's containers. Implementations are expected to be backed by a
+// specific container runtime (Docker, CRI, ...).
+type PodInfoGetter interface {
+	GetPodInfo(host, podID string) (PodInfo, error)
+}
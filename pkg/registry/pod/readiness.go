@@ -0,0 +1,196 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+
+	"github.com/golang/glog"
+)
+
+// podWatcher is the subset of Registry that podReadinessTracker needs. It
+// exists so the tracker can be exercised with a fake in tests without
+// pulling in the rest of the Registry interface.
+type podWatcher interface {
+	GetPod(id string) (*api.Pod, error)
+	WatchPods(resourceVersion uint64) (watch.Interface, error)
+}
+
+// reconnectBackoff is how long run() waits before calling WatchPods again
+// after a failed or closed watch. A var rather than a const so tests can
+// shrink it.
+var reconnectBackoff = time.Second
+
+// waitForPodRecheckInterval bounds how long WaitFor can miss a readiness
+// event delivered during a watch reconnect: it re-checks the registry
+// directly at this cadence as a backstop to the event-driven path.
+const waitForPodRecheckInterval = 10 * time.Second
+
+// podReadinessTracker lets callers block until a pod reaches PodRunning or
+// PodTerminated, without each caller polling the registry on its own
+// goroutine. A single goroutine consumes the registry's pod watch and
+// fans out to registered waiters.
+type podReadinessTracker struct {
+	registry podWatcher
+	// statusFor computes a pod's status, enriching it with live container
+	// info first (as RegistryStorage.Get does) since the registry and its
+	// watch only ever hand back the pod as last persisted.
+	statusFor func(*api.Pod) api.PodStatus
+
+	mu      sync.Mutex
+	waiters map[string][]chan api.Pod
+}
+
+// newPodReadinessTracker starts the tracker's watch goroutine and returns
+// it. The tracker runs for the lifetime of the process; there is no Stop.
+func newPodReadinessTracker(registry podWatcher, statusFor func(*api.Pod) api.PodStatus) *podReadinessTracker {
+	t := &podReadinessTracker{
+		registry:  registry,
+		statusFor: statusFor,
+		waiters:   make(map[string][]chan api.Pod),
+	}
+	go t.run()
+	return t
+}
+
+// run consumes registry watch events until the process exits, reconnecting
+// if the watch channel is closed (e.g. because the requested
+// resourceVersion fell out of the registry's history).
+func (t *podReadinessTracker) run() {
+	for {
+		w, err := t.registry.WatchPods(0)
+		if err != nil {
+			glog.Errorf("podReadinessTracker: unable to watch pods, retrying: %v", err)
+			time.Sleep(reconnectBackoff)
+			continue
+		}
+		for event := range w.ResultChan() {
+			pod, ok := event.Object.(*api.Pod)
+			if !ok {
+				continue
+			}
+			t.notify(*pod)
+		}
+		glog.Infof("podReadinessTracker: watch channel closed, reconnecting")
+		time.Sleep(reconnectBackoff)
+	}
+}
+
+// notify wakes any waiters registered for pod.ID if pod has reached a
+// terminal scheduling state. statusFor can trigger a live runtime RPC
+// (e.g. podInfoGetter.GetPodInfo on a cache miss), so it's only called
+// when a waiter is actually registered for this pod; otherwise every pod
+// event cluster-wide would serialize runtime calls on this one goroutine.
+func (t *podReadinessTracker) notify(pod api.Pod) {
+	t.mu.Lock()
+	_, waiting := t.waiters[pod.ID]
+	t.mu.Unlock()
+	if !waiting {
+		return
+	}
+
+	status := t.statusFor(&pod)
+	switch status {
+	case api.PodRunning, api.PodTerminated:
+	default:
+		return
+	}
+	pod.CurrentState.Status = status
+
+	t.mu.Lock()
+	waiters := t.waiters[pod.ID]
+	delete(t.waiters, pod.ID)
+	t.mu.Unlock()
+
+	for _, ch := range waiters {
+		ch <- pod
+	}
+}
+
+// WaitFor blocks until pod id reaches PodRunning or PodTerminated, or until
+// timeout elapses. It is safe to call concurrently for the same id.
+func (t *podReadinessTracker) WaitFor(id string, timeout time.Duration) (api.Pod, error) {
+	ch := make(chan api.Pod, 1)
+
+	t.mu.Lock()
+	t.waiters[id] = append(t.waiters[id], ch)
+	t.mu.Unlock()
+	defer t.removeWaiter(id, ch)
+
+	// The pod may already be ready from an event that arrived before we
+	// registered above; check current state rather than relying solely on
+	// the watch to catch up.
+	if pod, ready := t.checkCurrent(id); ready {
+		return pod, nil
+	}
+
+	deadline := time.After(timeout)
+	// Back up the event-driven path with a periodic direct check, so a
+	// readiness event delivered while run() is reconnecting its watch
+	// isn't missed for the full timeout.
+	recheck := time.NewTicker(waitForPodRecheckInterval)
+	defer recheck.Stop()
+	for {
+		select {
+		case pod := <-ch:
+			return pod, nil
+		case <-recheck.C:
+			if pod, ready := t.checkCurrent(id); ready {
+				return pod, nil
+			}
+		case <-deadline:
+			return api.Pod{}, fmt.Errorf("timed out waiting for pod %q to become ready", id)
+		}
+	}
+}
+
+// checkCurrent fetches pod id directly from the registry and reports
+// whether it has already reached a terminal scheduling state.
+func (t *podReadinessTracker) checkCurrent(id string) (api.Pod, bool) {
+	current, err := t.registry.GetPod(id)
+	if err != nil || current == nil {
+		return api.Pod{}, false
+	}
+	status := t.statusFor(current)
+	switch status {
+	case api.PodRunning, api.PodTerminated:
+		current.CurrentState.Status = status
+		return *current, true
+	default:
+		return api.Pod{}, false
+	}
+}
+
+func (t *podReadinessTracker) removeWaiter(id string, ch chan api.Pod) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	waiters := t.waiters[id]
+	for i, w := range waiters {
+		if w == ch {
+			t.waiters[id] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(t.waiters[id]) == 0 {
+		delete(t.waiters, id)
+	}
+}
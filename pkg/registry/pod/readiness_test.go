@@ -0,0 +1,271 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// fakeWatch is a minimal watch.Interface whose events are driven directly
+// by tests.
+type fakeWatch struct {
+	events chan watch.Event
+}
+
+func newFakeWatch() *fakeWatch {
+	return &fakeWatch{events: make(chan watch.Event, 10)}
+}
+
+func (f *fakeWatch) ResultChan() <-chan watch.Event { return f.events }
+func (f *fakeWatch) Stop()                          { close(f.events) }
+
+// fakePodWatcher is a minimal podWatcher backed by a manually driven watch
+// channel, so tests can control exactly when events are delivered. Each
+// WatchPods call hands back a fresh fakeWatch, mirroring a real registry
+// reconnect, and the latest one is what setPod/disconnect act on.
+type fakePodWatcher struct {
+	mu      sync.Mutex
+	pods    map[string]*api.Pod
+	watches []*fakeWatch
+}
+
+func newFakePodWatcher() *fakePodWatcher {
+	return &fakePodWatcher{pods: map[string]*api.Pod{}}
+}
+
+func (f *fakePodWatcher) GetPod(id string) (*api.Pod, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pods[id], nil
+}
+
+func (f *fakePodWatcher) WatchPods(resourceVersion uint64) (watch.Interface, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := newFakeWatch()
+	f.watches = append(f.watches, w)
+	return w, nil
+}
+
+func (f *fakePodWatcher) latest() *fakeWatch {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.watches[len(f.watches)-1]
+}
+
+// setPod updates the fake registry's view of a pod and, if notify is true,
+// emits a watch event for it on the most recently issued watch.
+func (f *fakePodWatcher) setPod(pod api.Pod, notify bool) {
+	f.mu.Lock()
+	f.pods[pod.ID] = &pod
+	f.mu.Unlock()
+	if notify {
+		f.latest().events <- watch.Event{Type: watch.Modified, Object: &pod}
+	}
+}
+
+// disconnect closes the most recently issued watch, forcing run() to
+// reconnect by calling WatchPods again.
+func (f *fakePodWatcher) disconnect() {
+	f.latest().Stop()
+}
+
+// watchCount reports how many times WatchPods has been called.
+func (f *fakePodWatcher) watchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watches)
+}
+
+func runningPod(id string) api.Pod {
+	return api.Pod{
+		ID: id,
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{
+				Containers: []api.Container{{Name: "app"}},
+			},
+		},
+		CurrentState: api.PodState{
+			Host: "node-1",
+			Info: client.PodInfo{
+				Containers: map[string]client.ContainerStatus{
+					"app": {Running: true},
+				},
+			},
+		},
+	}
+}
+
+func TestPodReadinessTrackerEventAfterRegister(t *testing.T) {
+	fake := newFakePodWatcher()
+	tracker := newPodReadinessTracker(fake, makePodStatus)
+
+	pod := runningPod("pod-a")
+	done := make(chan struct{})
+	go func() {
+		got, err := tracker.WaitFor("pod-a", time.Second)
+		if err != nil {
+			t.Errorf("WaitFor returned error: %v", err)
+		}
+		if got.ID != "pod-a" {
+			t.Errorf("WaitFor returned pod %q, want pod-a", got.ID)
+		}
+		if got.CurrentState.Status != api.PodRunning {
+			t.Errorf("WaitFor returned status %q, want %q", got.CurrentState.Status, api.PodRunning)
+		}
+		close(done)
+	}()
+
+	// Give WaitFor a chance to register before the event arrives.
+	time.Sleep(10 * time.Millisecond)
+	fake.setPod(pod, true)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitFor did not return after event arrived")
+	}
+}
+
+func TestPodReadinessTrackerEventBeforeRegister(t *testing.T) {
+	fake := newFakePodWatcher()
+	tracker := newPodReadinessTracker(fake, makePodStatus)
+
+	pod := runningPod("pod-b")
+	// The pod is already running in the registry by the time WaitFor is
+	// called; no watch event will ever arrive for it.
+	fake.setPod(pod, false)
+
+	got, err := tracker.WaitFor("pod-b", time.Second)
+	if err != nil {
+		t.Fatalf("WaitFor returned error: %v", err)
+	}
+	if got.ID != "pod-b" {
+		t.Errorf("WaitFor returned pod %q, want pod-b", got.ID)
+	}
+	if got.CurrentState.Status != api.PodRunning {
+		t.Errorf("WaitFor returned status %q, want %q", got.CurrentState.Status, api.PodRunning)
+	}
+}
+
+func TestPodReadinessTrackerSkipsStatusForWithoutWaiters(t *testing.T) {
+	fake := newFakePodWatcher()
+	var calls int32
+	countingStatus := func(pod *api.Pod) api.PodStatus {
+		atomic.AddInt32(&calls, 1)
+		return makePodStatus(pod)
+	}
+	newPodReadinessTracker(fake, countingStatus)
+
+	// Wait for the tracker's watch so setPod has a channel to send on.
+	for fake.watchCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// No one is waiting on this pod; notify() must not bother computing
+	// its status (that can trigger a live runtime RPC).
+	fake.setPod(runningPod("pod-nobody-waits"), true)
+	time.Sleep(20 * time.Millisecond)
+
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("statusFor was called %d times for a pod with no waiters, want 0", n)
+	}
+}
+
+func TestPodReadinessTrackerMultipleWaiters(t *testing.T) {
+	fake := newFakePodWatcher()
+	tracker := newPodReadinessTracker(fake, makePodStatus)
+
+	pod := runningPod("pod-c")
+	const waiters = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tracker.WaitFor("pod-c", time.Second); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	fake.setPod(pod, true)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("waiter returned error: %v", err)
+	}
+}
+
+func TestPodReadinessTrackerWatchReconnect(t *testing.T) {
+	old := reconnectBackoff
+	reconnectBackoff = time.Millisecond
+	defer func() { reconnectBackoff = old }()
+
+	fake := newFakePodWatcher()
+	tracker := newPodReadinessTracker(fake, makePodStatus)
+
+	// Wait for the tracker's initial watch, then kill it; run() should
+	// reconnect by calling WatchPods again.
+	for fake.watchCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.disconnect()
+	for fake.watchCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pod := runningPod("pod-d")
+	done := make(chan struct{})
+	go func() {
+		got, err := tracker.WaitFor("pod-d", time.Second)
+		if err != nil {
+			t.Errorf("WaitFor returned error: %v", err)
+		}
+		if got.ID != "pod-d" {
+			t.Errorf("WaitFor returned pod %q, want pod-d", got.ID)
+		}
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	fake.setPod(pod, true)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitFor did not return after post-reconnect event arrived")
+	}
+}
+
+func TestPodReadinessTrackerTimeout(t *testing.T) {
+	fake := newFakePodWatcher()
+	tracker := newPodReadinessTracker(fake, makePodStatus)
+
+	if _, err := tracker.WaitFor("never-comes", 20*time.Millisecond); err == nil {
+		t.Fatal("expected timeout error, got nil")
+	}
+}
@@ -37,36 +37,64 @@ import (
 
 // RegistryStorage implements the RESTStorage interface in terms of a PodRegistry
 type RegistryStorage struct {
-	cloudProvider cloudprovider.Interface
-	mu            sync.Mutex
-	minionLister  scheduler.MinionLister
-	podCache      client.PodInfoGetter
-	podInfoGetter client.PodInfoGetter
-	podPollPeriod time.Duration
-	registry      Registry
-	scheduler     scheduler.Scheduler
+	cloudProvider    cloudprovider.Interface
+	mu               sync.Mutex
+	minionLister     scheduler.MinionLister
+	podCache         client.PodInfoGetter
+	podInfoGetter    client.PodInfoGetter
+	readiness        *podReadinessTracker
+	readinessTimeout time.Duration
+	registry         Registry
+	scheduler        scheduler.Scheduler
 }
 
 type RegistryStorageConfig struct {
 	CloudProvider cloudprovider.Interface
 	MinionLister  scheduler.MinionLister
 	PodCache      client.PodInfoGetter
+	// PodInfoGetter talks to the node's container runtime to fetch live
+	// pod state. Pass a *client.DockerPodInfoGetter or a
+	// *client.CRIPodInfoGetter depending on which runtime the cluster runs.
 	PodInfoGetter client.PodInfoGetter
 	Registry      Registry
 	Scheduler     scheduler.Scheduler
+	// ReadinessTimeout bounds how long Create/Update wait for a pod to
+	// reach PodRunning or PodTerminated before giving up. Defaults to 2
+	// minutes if zero.
+	ReadinessTimeout time.Duration
 }
 
+// defaultReadinessTimeout is used when RegistryStorageConfig.ReadinessTimeout
+// is unset.
+const defaultReadinessTimeout = 2 * time.Minute
+
 // NewRegistryStorage returns a new RegistryStorage.
 func NewRegistryStorage(config *RegistryStorageConfig) apiserver.RESTStorage {
-	return &RegistryStorage{
-		cloudProvider: config.CloudProvider,
-		minionLister:  config.MinionLister,
-		podCache:      config.PodCache,
-		podInfoGetter: config.PodInfoGetter,
-		podPollPeriod: time.Second * 10,
-		registry:      config.Registry,
-		scheduler:     config.Scheduler,
+	readinessTimeout := config.ReadinessTimeout
+	if readinessTimeout == 0 {
+		readinessTimeout = defaultReadinessTimeout
+	}
+	rs := &RegistryStorage{
+		cloudProvider:    config.CloudProvider,
+		minionLister:     config.MinionLister,
+		podCache:         config.PodCache,
+		podInfoGetter:    config.PodInfoGetter,
+		readinessTimeout: readinessTimeout,
+		registry:         config.Registry,
+		scheduler:        config.Scheduler,
 	}
+	rs.readiness = newPodReadinessTracker(config.Registry, rs.podStatus)
+	return rs
+}
+
+// podStatus computes pod's status, first enriching it with live container
+// info the same way Get does. Used by the readiness tracker, which only
+// ever sees pods as persisted in the registry or its watch.
+func (rs *RegistryStorage) podStatus(pod *api.Pod) api.PodStatus {
+	if rs.podCache != nil || rs.podInfoGetter != nil {
+		rs.fillPodInfo(pod)
+	}
+	return makePodStatus(pod)
 }
 
 func (rs *RegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
@@ -107,7 +135,12 @@ func (rs *RegistryStorage) Get(id string) (interface{}, error) {
 		rs.fillPodInfo(pod)
 		pod.CurrentState.Status = makePodStatus(pod)
 	}
-	pod.CurrentState.HostIP = getInstanceIP(rs.cloudProvider, pod.CurrentState.Host)
+	// Prefer the runtime-reported HostIP (e.g. from a CRI sandbox) over the
+	// cloud provider lookup, falling back to the latter when the runtime
+	// didn't report one.
+	if pod.CurrentState.HostIP == "" {
+		pod.CurrentState.HostIP = getInstanceIP(rs.cloudProvider, pod.CurrentState.Host)
+	}
 	return pod, err
 }
 
@@ -177,16 +210,8 @@ func (rs *RegistryStorage) fillPodInfo(pod *api.Pod) {
 			}
 		}
 		pod.CurrentState.Info = info
-		netContainerInfo, ok := info["net"]
-		if ok {
-			if netContainerInfo.NetworkSettings != nil {
-				pod.CurrentState.PodIP = netContainerInfo.NetworkSettings.IPAddress
-			} else {
-				glog.Warningf("No network settings: %#v", netContainerInfo)
-			}
-		} else {
-			glog.Warningf("Couldn't find network container for %s in %v", pod.ID, info)
-		}
+		pod.CurrentState.PodIP = info.PodIP
+		pod.CurrentState.HostIP = info.HostIP
 	}
 }
 
@@ -211,15 +236,15 @@ func getInstanceIP(cloud cloudprovider.Interface, host string) string {
 }
 
 func makePodStatus(pod *api.Pod) api.PodStatus {
-	if pod.CurrentState.Info == nil || pod.CurrentState.Host == "" {
+	if pod.CurrentState.Info.Containers == nil || pod.CurrentState.Host == "" {
 		return api.PodWaiting
 	}
 	running := 0
 	stopped := 0
 	unknown := 0
 	for _, container := range pod.DesiredState.Manifest.Containers {
-		if info, ok := pod.CurrentState.Info[container.Name]; ok {
-			if info.State.Running {
+		if status, ok := pod.CurrentState.Info.Containers[container.Name]; ok {
+			if status.Running {
 				running++
 			} else {
 				stopped++
@@ -252,22 +277,5 @@ func (rs *RegistryStorage) scheduleAndCreatePod(pod api.Pod) error {
 }
 
 func (rs *RegistryStorage) waitForPodRunning(pod api.Pod) (interface{}, error) {
-	for {
-		podObj, err := rs.Get(pod.ID)
-		if err != nil || podObj == nil {
-			return nil, err
-		}
-		podPtr, ok := podObj.(*api.Pod)
-		if !ok {
-			// This should really never happen.
-			return nil, fmt.Errorf("Error %#v is not an api.Pod!", podObj)
-		}
-		switch podPtr.CurrentState.Status {
-		case api.PodRunning, api.PodTerminated:
-			return pod, nil
-		default:
-			time.Sleep(rs.podPollPeriod)
-		}
-	}
-	return pod, nil
-}
\ No newline at end of file
+	return rs.readiness.WaitFor(pod.ID, rs.readinessTimeout)
+}